@@ -0,0 +1,275 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// job is a single node of the chain AST produced by parseLine. It holds the
+// argv for one command plus, optionally, the node to run next depending on
+// whether this command succeeds or fails.
+type job struct {
+	args []string
+
+	onSuccess *job
+	onFailure *job
+}
+
+// runnerFunc executes a single job's argv and reports whether it succeeded.
+// It is injected so job chains can be executed against the real s5cmd App
+// in production and against a fake in tests.
+type runnerFunc func(ctx context.Context, args []string) error
+
+// run executes the job chain rooted at j: j runs first, then depending on
+// its result the onSuccess or onFailure branch runs, recursively. The chain
+// is considered failed only if the last node that actually ran failed.
+func (j *job) run(ctx context.Context, runner runnerFunc, stats *chainStats) error {
+	if j == nil {
+		return nil
+	}
+
+	err := runner(ctx, j.args)
+	if err != nil {
+		stats.recordFailure()
+		if j.onFailure != nil {
+			return j.onFailure.run(ctx, runner, stats)
+		}
+		return err
+	}
+
+	stats.recordSuccess()
+	if j.onSuccess != nil {
+		return j.onSuccess.run(ctx, runner, stats)
+	}
+	return nil
+}
+
+// chainStats aggregates per-node success/failure counts across every job
+// executed as part of a run script, including chained success/failure
+// branches. Chains run concurrently (run.go bounds them to numWorkers at a
+// time), so the counters are updated atomically.
+type chainStats struct {
+	numSuccess uint64
+	numFails   uint64
+}
+
+func (s *chainStats) recordSuccess() { atomic.AddUint64(&s.numSuccess, 1) }
+func (s *chainStats) recordFailure() { atomic.AddUint64(&s.numFails, 1) }
+
+// summary renders the counters in the same tabular style as `select --stats`.
+func (s *chainStats) summary() string {
+	return fmt.Sprintf(
+		"\n%-20s%20d\n%-20s%20d",
+		"Succeeded:", atomic.LoadUint64(&s.numSuccess),
+		"Failed:", atomic.LoadUint64(&s.numFails),
+	)
+}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokAndAnd
+	tokOrOr
+	tokSemicolon
+	tokEOF
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// tokenize splits a run-script line into words and the &&, ||, ; operators.
+// Words may be quoted with single or double quotes so that operators and
+// whitespace can appear inside an argument; a "#" outside of quotes starts
+// a comment that runs to the end of the line.
+func tokenize(line string) ([]token, error) {
+	var tokens []token
+
+	runes := []rune(line)
+	i, n := 0, len(runes)
+
+	for i < n {
+		c := runes[i]
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '#':
+			i = n // rest of the line is a comment
+		case c == '&' && i+1 < n && runes[i+1] == '&':
+			tokens = append(tokens, token{kind: tokAndAnd})
+			i += 2
+		case c == '|' && i+1 < n && runes[i+1] == '|':
+			tokens = append(tokens, token{kind: tokOrOr})
+			i += 2
+		case c == ';':
+			tokens = append(tokens, token{kind: tokSemicolon})
+			i++
+		default:
+			word, consumed, err := readWord(runes[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokWord, text: word})
+			i += consumed
+		}
+	}
+
+	return tokens, nil
+}
+
+// readWord reads a single (possibly quoted) word from the start of runes,
+// stopping at unquoted whitespace, "&&", "||", ";" or "#".
+func readWord(runes []rune) (string, int, error) {
+	var b strings.Builder
+
+	i, n := 0, len(runes)
+	for i < n {
+		c := runes[i]
+
+		switch {
+		case c == '\'' || c == '"':
+			quote := c
+			i++
+			start := i
+			for i < n && runes[i] != quote {
+				i++
+			}
+			if i == n {
+				return "", 0, fmt.Errorf("unterminated %c quote", quote)
+			}
+			b.WriteString(string(runes[start:i]))
+			i++ // consume closing quote
+		case unicode.IsSpace(c), c == '#', c == ';':
+			return b.String(), i, nil
+		case c == '&' && i+1 < n && runes[i+1] == '&':
+			return b.String(), i, nil
+		case c == '|' && i+1 < n && runes[i+1] == '|':
+			return b.String(), i, nil
+		default:
+			b.WriteRune(c)
+			i++
+		}
+	}
+
+	return b.String(), i, nil
+}
+
+// parseLine parses a run-script line into zero or more independent job
+// chains, one per ";"-separated segment. Each chain may itself contain
+// "&&"/"||"-linked nodes.
+func parseLine(line string) ([]*job, error) {
+	tokens, err := tokenize(line)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &jobParser{tokens: tokens}
+
+	var jobs []*job
+	for p.peek().kind != tokEOF {
+		j, err := p.parseChain()
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+
+		if p.peek().kind == tokSemicolon {
+			p.next()
+			continue
+		}
+		break
+	}
+
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("unexpected token in %q", line)
+	}
+
+	return jobs, nil
+}
+
+// jobParser is a small recursive-descent parser over a token stream
+// produced by tokenize.
+type jobParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *jobParser) peek() token {
+	if p.pos >= len(p.tokens) {
+		return token{kind: tokEOF}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *jobParser) next() token {
+	t := p.peek()
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// parseChain parses "cmd", "cmd && cmd", "cmd || cmd" or "cmd && cmd || cmd",
+// grafting the success and/or failure command onto the *first* command, the
+// same shape core.ParseJob's regexCmdAndOr produced. Anything deeper, like
+// "a && b && c", is rejected rather than guessed at: a trailing "&&"/"||"
+// after the failure/success branch has already been attached means the
+// branch command is itself a chain, which core.parseSingleJob never allowed.
+func (p *jobParser) parseChain() (*job, error) {
+	root, err := p.parseCommand()
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.peek().kind {
+	case tokAndAnd:
+		p.next()
+		n, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		root.onSuccess = n
+
+		if p.peek().kind == tokOrOr {
+			p.next()
+			f, err := p.parseCommand()
+			if err != nil {
+				return nil, err
+			}
+			root.onFailure = f
+		}
+	case tokOrOr:
+		p.next()
+		n, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		root.onFailure = n
+	}
+
+	if p.peek().kind == tokAndAnd || p.peek().kind == tokOrOr {
+		return nil, errors.New("nested commands are not supported")
+	}
+
+	return root, nil
+}
+
+func (p *jobParser) parseCommand() (*job, error) {
+	var args []string
+	for p.peek().kind == tokWord {
+		args = append(args, p.next().text)
+	}
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("expected a command")
+	}
+
+	return &job{args: args}, nil
+}
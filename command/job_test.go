@@ -0,0 +1,113 @@
+package command
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseLineSingleCommand(t *testing.T) {
+	jobs, err := parseLine(`cp a.txt s3://bucket/`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("expected a single job, got %d", len(jobs))
+	}
+	want := []string{"cp", "a.txt", "s3://bucket/"}
+	if !reflect.DeepEqual(jobs[0].args, want) {
+		t.Errorf("args = %v, want %v", jobs[0].args, want)
+	}
+}
+
+func TestParseLineAndOr(t *testing.T) {
+	jobs, err := parseLine(`cp a.txt s3://bucket/ && rm a.txt || echo failed`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("expected a single chain, got %d", len(jobs))
+	}
+
+	root := jobs[0]
+	if root.onSuccess == nil || root.onSuccess.args[0] != "rm" {
+		t.Fatalf("expected onSuccess to be the rm command, got %+v", root.onSuccess)
+	}
+	if root.onFailure == nil || root.onFailure.args[0] != "echo" {
+		t.Fatalf("expected onFailure to be the echo command, got %+v", root.onFailure)
+	}
+}
+
+func TestParseLineSemicolonIsIndependent(t *testing.T) {
+	jobs, err := parseLine(`cp a.txt s3://bucket/ ; cp b.txt s3://bucket/`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("expected two independent jobs, got %d", len(jobs))
+	}
+}
+
+func TestParseLineQuotedArguments(t *testing.T) {
+	jobs, err := parseLine(`echo "hello && world"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"echo", "hello && world"}
+	if !reflect.DeepEqual(jobs[0].args, want) {
+		t.Errorf("args = %v, want %v", jobs[0].args, want)
+	}
+}
+
+func TestParseLineStripsComments(t *testing.T) {
+	jobs, err := parseLine(`cp a.txt s3://bucket/ # upload the file`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"cp", "a.txt", "s3://bucket/"}
+	if !reflect.DeepEqual(jobs[0].args, want) {
+		t.Errorf("args = %v, want %v", jobs[0].args, want)
+	}
+}
+
+func TestJobRunNestedRejection(t *testing.T) {
+	// a && b && c chains a second operator onto an already-attached branch,
+	// which core.parseSingleJob never allowed ("Nested commands are not
+	// supported"); parseLine must reject it rather than guess at a shape.
+	if _, err := parseLine(`a && b && c`); err == nil {
+		t.Fatal("expected an error for a chain deeper than one && and one ||")
+	}
+}
+
+func TestChainStatsSummary(t *testing.T) {
+	var stats chainStats
+	stats.recordSuccess()
+	stats.recordSuccess()
+	stats.recordFailure()
+
+	want := "\nSucceeded:                             2\nFailed:                                1"
+	if got := stats.summary(); got != want {
+		t.Errorf("summary() = %q, want %q", got, want)
+	}
+}
+
+func TestJobRunShortCircuitsOnSuccess(t *testing.T) {
+	jobs, err := parseLine(`a || b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ran []string
+	runner := func(ctx context.Context, args []string) error {
+		ran = append(ran, args[0])
+		return nil
+	}
+
+	var stats chainStats
+	if err := jobs[0].run(context.Background(), runner, &stats); err != nil {
+		t.Fatalf("expected the chain to succeed, got %v", err)
+	}
+	if !reflect.DeepEqual(ran, []string{"a"}) {
+		t.Errorf("ran = %v, want only [a] to have executed since it succeeded", ran)
+	}
+}
@@ -0,0 +1,180 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/log/stat"
+	"github.com/peak/s5cmd/parallel"
+)
+
+var runHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} [options] [file]
+
+Options:
+	{{range .VisibleFlags}}{{.}}
+	{{end}}
+Examples:
+	01. Run the commands declared in file in parallel
+		 > s5cmd {{.HelpName}} file
+
+	02. Run commands piped from another process, chaining on success/failure
+		 > echo 'cp a.txt s3://bucket/ && rm a.txt || echo "upload failed"' | s5cmd {{.HelpName}}
+`
+
+var runCommandFlags = []cli.Flag{
+	&cli.IntFlag{
+		Name:  "numworkers",
+		Usage: "Number of workers executing jobs in parallel",
+		Value: 256,
+	},
+	&cli.BoolFlag{
+		Name:  "stats",
+		Usage: "Print a summary of succeeded/failed jobs at the end",
+	},
+}
+
+var runCommand = &cli.Command{
+	Name:               "run",
+	HelpName:           "run",
+	Usage:              "run commands in batch",
+	Flags:              runCommandFlags,
+	CustomHelpTemplate: runHelpTemplate,
+	Action: func(c *cli.Context) (err error) {
+		defer stat.Collect(c.Command.FullName(), &err)()
+
+		return Run{
+			src:         c.Args().Get(0),
+			op:          c.Command.Name,
+			fullCommand: givenCommand(c),
+			numWorkers:  c.Int("numworkers"),
+			printStats:  c.Bool("stats"),
+			app:         c.App,
+		}.Run(c.Context)
+	},
+}
+
+// Run holds run-command flags and state. It reads a batch of s5cmd command
+// lines, either from a file or from stdin, and executes them in parallel,
+// honoring && / || / ; chaining between commands on the same line.
+type Run struct {
+	src         string
+	op          string
+	fullCommand string
+
+	numWorkers int
+	printStats bool
+	app        *cli.App
+
+	// runner executes a single job's argv. It defaults to runSingle, which
+	// dispatches into the real s5cmd App; tests substitute a fake so that
+	// the numWorkers bound can be verified without running actual commands.
+	runner runnerFunc
+}
+
+// Run reads the run script and executes every line's job chain
+// concurrently, up to numWorkers chains at a time.
+func (r Run) Run(ctx context.Context) error {
+	src, err := r.open()
+	if err != nil {
+		printError(r.fullCommand, r.op, err)
+		return err
+	}
+	defer src.Close()
+
+	runner := r.runner
+	if runner == nil {
+		runner = r.runSingle
+	}
+
+	numWorkers := r.numWorkers
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	sem := make(chan struct{}, numWorkers)
+
+	var merror error
+	var stats chainStats
+
+	waiter := parallel.NewWaiter()
+	errDoneCh := make(chan bool)
+
+	go func() {
+		defer close(errDoneCh)
+		for err := range waiter.Err() {
+			printError(r.fullCommand, r.op, err)
+			merror = multierror.Append(merror, err)
+		}
+	}()
+
+	scanner := bufio.NewScanner(src)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if blankOrComment(line) {
+			continue
+		}
+
+		chains, err := parseLine(line)
+		if err != nil {
+			err = fmt.Errorf("%q: %v", line, err)
+			printError(r.fullCommand, r.op, err)
+			merror = multierror.Append(merror, err)
+			continue
+		}
+
+		for _, chain := range chains {
+			chain := chain
+			task := func() error {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				return chain.run(ctx, runner, &stats)
+			}
+			parallel.Run(task, waiter)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		merror = multierror.Append(merror, err)
+	}
+
+	waiter.Wait()
+	<-errDoneCh
+
+	if r.printStats {
+		fmt.Fprintln(os.Stdout, stats.summary())
+	}
+
+	return merror
+}
+
+// runSingle executes a single command's argv against the s5cmd App, the
+// same way a command typed on the command line would run.
+func (r Run) runSingle(ctx context.Context, args []string) error {
+	return r.app.RunContext(ctx, append([]string{r.app.Name}, args...))
+}
+
+func (r Run) open() (io.ReadCloser, error) {
+	if r.src == "" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(r.src)
+}
+
+func blankOrComment(line string) bool {
+	for _, c := range line {
+		if c == ' ' || c == '\t' {
+			continue
+		}
+		return c == '#'
+	}
+	return true
+}
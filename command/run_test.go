@@ -0,0 +1,58 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunRespectsNumWorkers(t *testing.T) {
+	const numWorkers = 3
+	const numLines = 12
+
+	f, err := os.CreateTemp("", "s5cmd-run-test-*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	for i := 0; i < numLines; i++ {
+		fmt.Fprintf(f, "cmd%d\n", i)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var inFlight, maxInFlight int64
+
+	runner := func(ctx context.Context, args []string) error {
+		n := atomic.AddInt64(&inFlight, 1)
+		for {
+			max := atomic.LoadInt64(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt64(&maxInFlight, max, n) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		atomic.AddInt64(&inFlight, -1)
+		return nil
+	}
+
+	r := Run{src: f.Name(), numWorkers: numWorkers, runner: runner}
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	got := atomic.LoadInt64(&maxInFlight)
+	if got > numWorkers {
+		t.Errorf("max concurrent jobs = %d, want at most %d", got, numWorkers)
+	}
+	if got < 2 {
+		t.Errorf("max concurrent jobs = %d, want more than 1 to show jobs actually ran in parallel", got)
+	}
+}
@@ -2,9 +2,14 @@ package command
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/urfave/cli/v2"
@@ -13,6 +18,7 @@ import (
 	"github.com/peak/s5cmd/log/stat"
 	"github.com/peak/s5cmd/parallel"
 	"github.com/peak/s5cmd/storage"
+	"github.com/peak/s5cmd/storage/filter"
 	"github.com/peak/s5cmd/storage/url"
 )
 
@@ -20,7 +26,7 @@ var selectHelpTemplate = `Name:
 	{{.HelpName}} - {{.Usage}}
 
 Usage:
-	{{.HelpName}} [options] argument
+	{{.HelpName}} [options] source [destination]
 
 Options:
 	{{range .VisibleFlags}}{{.}}
@@ -28,8 +34,80 @@ Options:
 Examples:
 	01. Search for all JSON objects with the foo property set to 'bar' and spit them into stdout
 		 > s5cmd {{.HelpName}} --compression-type gzip --query "SELECT * FROM S3Object s WHERE s.foo='bar'" s3://bucket/*
+
+	02. Run the same search, writing one result object per source object under a destination prefix
+		 > s5cmd {{.HelpName}} --query "SELECT * FROM S3Object s WHERE s.foo='bar'" s3://bucket/* s3://other-bucket/results/
 `
 
+// patternFlagSets hands off the ordered pattern slice built up while a
+// --exclude/--include pair of patternFlags is applied to a single
+// *flag.FlagSet, so that invocation's two flags can share one slice that no
+// other invocation sees. The map entry is removed once the second flag of
+// the pair has claimed the slice. A *flag.FlagSet is built
+// fresh for every command invocation (including concurrent in-process
+// invocations from "run"), so keying on it, rather than on a shared global,
+// keeps concurrent "select" invocations from racing on or cross-
+// contaminating each other's --exclude/--include patterns.
+var (
+	patternFlagSetsMu sync.Mutex
+	patternFlagSets   = map[*flag.FlagSet]*[]filter.Pattern{}
+)
+
+// patternFlag implements cli.Flag for --exclude/--include. Unlike
+// cli.GenericFlag, whose Value field is fixed once at construction and so
+// would be reused (and thus shared and raced on) across every invocation of
+// this command in the process, patternFlag allocates its backing slice fresh
+// in Apply, which runs once per invocation.
+type patternFlag struct {
+	name    string
+	usage   string
+	include bool
+}
+
+func (f *patternFlag) String() string  { return fmt.Sprintf("--%s value\t%s", f.name, f.usage) }
+func (f *patternFlag) Names() []string { return []string{f.name} }
+
+// IsSet always reports false. Nothing in this command calls
+// c.IsSet("exclude") or c.IsSet("include"), and patternFlag's Flag struct is
+// shared across invocations (only its per-invocation patternValue isn't, see
+// Apply), so there's no safe per-invocation state to report here anyway.
+func (f *patternFlag) IsSet() bool { return false }
+
+func (f *patternFlag) Apply(set *flag.FlagSet) error {
+	patternFlagSetsMu.Lock()
+	patterns, ok := patternFlagSets[set]
+	if ok {
+		// The other half of this invocation's --exclude/--include pair
+		// already registered the shared slice; claim it so the map doesn't
+		// keep an entry around once both flags are wired up.
+		delete(patternFlagSets, set)
+	} else {
+		patterns = &[]filter.Pattern{}
+		patternFlagSets[set] = patterns
+	}
+	patternFlagSetsMu.Unlock()
+
+	set.Var(&patternValue{include: f.include, patterns: patterns}, f.name, f.usage)
+	return nil
+}
+
+// patternValue is the flag.Value registered for a single patternFlag. Its
+// patterns field is shared between the --exclude and --include flags of one
+// invocation, so interleaved patterns land in real command-line order
+// instead of being grouped by flag name, which two independent
+// cli.StringSliceFlag values can't do.
+type patternValue struct {
+	include  bool
+	patterns *[]filter.Pattern
+}
+
+func (patternValue) String() string { return "" }
+
+func (v *patternValue) Set(s string) error {
+	*v.patterns = append(*v.patterns, filter.Pattern{Pattern: s, Include: v.include})
+	return nil
+}
+
 var selectCommandFlags = []cli.Flag{
 	&cli.StringFlag{
 		Name:    "query",
@@ -40,6 +118,90 @@ var selectCommandFlags = []cli.Flag{
 		Name:  "compression-type",
 		Usage: "Type of compression used in storage",
 	},
+	&cli.StringFlag{
+		Name:  "input-format",
+		Usage: "Format of the input objects: json, csv or parquet",
+		Value: "json",
+	},
+	&cli.StringFlag{
+		Name:  "input-json-type",
+		Usage: "Type of JSON input: document or lines",
+		Value: "document",
+	},
+	&cli.StringFlag{
+		Name:  "csv-delimiter",
+		Usage: "Field delimiter used by the csv input/output",
+		Value: ",",
+	},
+	&cli.StringFlag{
+		Name:  "csv-quote",
+		Usage: "Quote character used by the csv input/output",
+		Value: `"`,
+	},
+	&cli.StringFlag{
+		Name:  "csv-header",
+		Usage: "How the first line of csv input is treated: none, use or ignore",
+		Value: "none",
+	},
+	&cli.StringFlag{
+		Name:  "csv-comments",
+		Usage: "Character marking a comment line to ignore in csv input",
+	},
+	&cli.BoolFlag{
+		Name:  "csv-allow-quoted-record-delimiter",
+		Usage: "Allow quoted record delimiters inside csv input",
+	},
+	&cli.StringFlag{
+		Name:  "output-format",
+		Usage: "Format of the emitted results: json or csv",
+		Value: "json",
+	},
+	&cli.StringFlag{
+		Name:  "output-json-record-delimiter",
+		Usage: "Record delimiter used by the json output",
+		Value: "\n",
+	},
+	&cli.StringFlag{
+		Name:  "output-csv-delimiter",
+		Usage: "Field delimiter used by the csv output",
+		Value: ",",
+	},
+	&cli.StringFlag{
+		Name:  "output-csv-quote",
+		Usage: "Quote character used by the csv output",
+		Value: `"`,
+	},
+	&cli.StringFlag{
+		Name:  "output-csv-quote-fields",
+		Usage: "When to quote csv output fields: always or asneeded",
+		Value: "asneeded",
+	},
+	&cli.StringFlag{
+		Name:  "result-separator",
+		Usage: "Separator written between results when they are concatenated into a single destination",
+		Value: "\n",
+	},
+	&patternFlag{
+		name:  "exclude",
+		usage: "Exclude objects with given pattern, evaluated in order together with --include",
+	},
+	&patternFlag{
+		name:    "include",
+		usage:   "Include objects with given pattern, evaluated in order together with --exclude",
+		include: true,
+	},
+	&cli.StringSliceFlag{
+		Name:  "exclude-from",
+		Usage: "Exclude objects with patterns listed in the given file, one per line",
+	},
+	&cli.BoolFlag{
+		Name:  "stats",
+		Usage: "Print a summary of scanned/processed/returned bytes at the end",
+	},
+	&cli.BoolFlag{
+		Name:  "progress",
+		Usage: "Print a live per-worker progress line to stderr",
+	},
 }
 
 var selectCommand = &cli.Command{
@@ -58,14 +220,45 @@ var selectCommand = &cli.Command{
 	Action: func(c *cli.Context) (err error) {
 		defer stat.Collect(c.Command.FullName(), &err)()
 
+		// exclude and include share a single patternValue.patterns slice for
+		// this invocation (see patternFlag.Apply), so either flag's Generic
+		// value gives back the full, correctly ordered list.
+		var filterPatterns []filter.Pattern
+		if v, ok := c.Generic("exclude").(*patternValue); ok {
+			filterPatterns = append([]filter.Pattern(nil), (*v.patterns)...)
+		}
+
 		return Select{
 			src:         c.Args().Get(0),
+			dst:         c.Args().Get(1),
 			op:          c.Command.Name,
 			fullCommand: givenCommand(c),
 			// flags
 			query:           c.String("query"),
 			compressionType: c.String("compression-type"),
 
+			inputFormat:                        c.String("input-format"),
+			inputJSONType:                      c.String("input-json-type"),
+			inputCSVDelimiter:                  c.String("csv-delimiter"),
+			inputCSVQuote:                      c.String("csv-quote"),
+			inputCSVHeaderInfo:                 c.String("csv-header"),
+			inputCSVComments:                   c.String("csv-comments"),
+			inputCSVAllowQuotedRecordDelimiter: c.Bool("csv-allow-quoted-record-delimiter"),
+
+			outputFormat:              c.String("output-format"),
+			outputJSONRecordDelimiter: c.String("output-json-record-delimiter"),
+			outputCSVDelimiter:        c.String("output-csv-delimiter"),
+			outputCSVQuote:            c.String("output-csv-quote"),
+			outputCSVQuoteFields:      c.String("output-csv-quote-fields"),
+
+			resultSeparator: c.String("result-separator"),
+
+			filterPatterns: filterPatterns,
+			excludeFrom:    c.StringSlice("exclude-from"),
+
+			printStats:    c.Bool("stats"),
+			printProgress: c.Bool("progress"),
+
 			storageOpts: NewStorageOpts(c),
 		}.Run(c.Context)
 	},
@@ -74,6 +267,7 @@ var selectCommand = &cli.Command{
 // Select holds select operation flags and states.
 type Select struct {
 	src         string
+	dst         string
 	op          string
 	fullCommand string
 
@@ -82,6 +276,28 @@ type Select struct {
 	query           string
 	compressionType string
 
+	inputFormat                        string
+	inputJSONType                      string
+	inputCSVDelimiter                  string
+	inputCSVQuote                      string
+	inputCSVHeaderInfo                 string
+	inputCSVComments                   string
+	inputCSVAllowQuotedRecordDelimiter bool
+
+	outputFormat              string
+	outputJSONRecordDelimiter string
+	outputCSVDelimiter        string
+	outputCSVQuote            string
+	outputCSVQuoteFields      string
+
+	resultSeparator string
+
+	filterPatterns []filter.Pattern
+	excludeFrom    []string
+
+	printStats    bool
+	printProgress bool
+
 	// s3 options
 	storageOpts storage.Options
 }
@@ -106,6 +322,25 @@ func (s Select) Run(ctx context.Context) error {
 		return err
 	}
 
+	matcher, err := filter.New(s.excludeFrom, s.filterPatterns)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	sink, err := s.newSink(ctx, client)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
+	var progress *progressPrinter
+	if s.printProgress {
+		progress = newProgressPrinter(os.Stderr, 200*time.Millisecond)
+	}
+
+	var stats selectStats
+
 	var merror error
 
 	waiter := parallel.NewWaiter()
@@ -129,44 +364,268 @@ func (s Select) Run(ctx context.Context) error {
 			continue
 		}
 
+		if !matcher.Match(object.URL.Path) {
+			continue
+		}
+
 		if object.StorageClass.IsGlacier() {
 			err := fmt.Errorf("object '%v' is on Glacier storage", object)
 			printError(s.fullCommand, s.op, err)
 			continue
 		}
 
-		task := s.prepareTask(ctx, client, object.URL)
+		task := s.prepareTask(ctx, client, object.URL, srcurl, sink, &stats, progress)
 		parallel.Run(task, waiter)
 	}
 
 	waiter.Wait()
 	<-errDoneCh
 
+	if progress != nil {
+		progress.Done()
+	}
+
+	if err := sink.Close(); err != nil {
+		merror = multierror.Append(merror, err)
+	}
+
+	if s.printStats {
+		fmt.Fprintln(os.Stdout, stats.summary())
+	}
+
 	return merror
 }
 
-func (s Select) prepareTask(ctx context.Context, client *storage.S3, url *url.URL) func() error {
+// resultSink decides where the output of each per-object select task is
+// written to: a per-object file under a destination prefix, a single
+// concatenated destination, or stdout.
+type resultSink struct {
+	mode            string // "stdout", "prefix" or "concat"
+	dst             *url.URL
+	client          *storage.S3
+	resultSeparator string
+
+	mu       sync.Mutex
+	wroteAny bool
+	shared   io.Writer
+	closeFn  func(error) error
+}
+
+// newSink builds the resultSink that prepareTask writes into, based on
+// whether a destination argument was given and whether it looks like a
+// prefix (ends with "/") or a single object/file.
+func (s Select) newSink(ctx context.Context, client *storage.S3) (*resultSink, error) {
+	if s.dst == "" {
+		return &resultSink{mode: "stdout", shared: os.Stdout, resultSeparator: s.resultSeparator}, nil
+	}
+
+	dsturl, err := url.New(s.dst)
+	if err != nil {
+		return nil, err
+	}
+
+	if dsturl.IsPrefix() {
+		return &resultSink{mode: "prefix", dst: dsturl, client: client}, nil
+	}
+
+	w, closeFn, err := openResultWriter(ctx, client, dsturl)
+	if err != nil {
+		return nil, err
+	}
+	return &resultSink{mode: "concat", shared: w, closeFn: closeFn, resultSeparator: s.resultSeparator}, nil
+}
+
+// writerFor returns the writer that a single object's select results should
+// be copied into, along with a function to call once writing is finished.
+// The returned func is passed the error (if any) that occurred while
+// writing, so that a partially written per-object destination can be
+// discarded instead of finalized.
+func (sk *resultSink) writerFor(ctx context.Context, srcurl, objurl *url.URL, ext string) (io.Writer, func(error) error, error) {
+	if sk.mode == "prefix" {
+		dst := sk.dst.Join(relativeKey(srcurl, objurl) + "." + ext)
+		w, closeFn, err := openResultWriter(ctx, sk.client, dst)
+		if err != nil {
+			return nil, nil, err
+		}
+		return w, closeFn, nil
+	}
+
+	return &syncWriter{sink: sk}, func(error) error { return nil }, nil
+}
+
+// Close finalizes the sink, flushing any destination opened once for the
+// whole run (stdout needs no finalization; per-object prefix writers are
+// already closed by writerFor's returned func).
+func (sk *resultSink) Close() error {
+	if sk.closeFn != nil {
+		return sk.closeFn(nil)
+	}
+	return nil
+}
+
+// syncWriter serializes writes from concurrent tasks into a resultSink's
+// shared writer, inserting resultSeparator once between consecutive objects'
+// results so that records from different objects are never interleaved
+// mid-record. A single object's select result commonly arrives as several
+// RecordsEvent frames, each forwarded via its own io.Copy-driven Write call,
+// so the separator decision is made once per syncWriter (i.e. once per
+// object, since writerFor hands out a fresh syncWriter per task) rather than
+// on every Write call.
+type syncWriter struct {
+	sink    *resultSink
+	started bool
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.sink.mu.Lock()
+	defer w.sink.mu.Unlock()
+
+	if !w.started {
+		w.started = true
+		if w.sink.wroteAny && w.sink.resultSeparator != "" {
+			if _, err := w.sink.shared.Write([]byte(w.sink.resultSeparator)); err != nil {
+				return 0, err
+			}
+		}
+		w.sink.wroteAny = true
+	}
+
+	return w.sink.shared.Write(p)
+}
+
+// openResultWriter opens a writer for a single destination URL, using the
+// local file system or an S3 multipart upload depending on the URL type.
+// The returned func finalizes the write: it is handed the error (if any)
+// that occurred while writing, so a failed write can be discarded instead
+// of leaving a truncated result at dst.
+func openResultWriter(ctx context.Context, client *storage.S3, dst *url.URL) (io.Writer, func(error) error, error) {
+	if !dst.IsRemote() {
+		// Write to a temporary file in the destination's directory and
+		// rename it into place on success, the same way cp writes local
+		// destinations atomically, so a failed select never leaves a
+		// partially written file at dst.
+		tmp, err := os.CreateTemp(filepath.Dir(dst.Path), filepath.Base(dst.Path)+".*.tmp")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		closeFn := func(writeErr error) error {
+			if writeErr != nil {
+				tmp.Close()
+				os.Remove(tmp.Name())
+				return nil
+			}
+			if err := tmp.Close(); err != nil {
+				os.Remove(tmp.Name())
+				return err
+			}
+			return os.Rename(tmp.Name(), dst.Path)
+		}
+
+		return tmp, closeFn, nil
+	}
+
+	pr, pw := io.Pipe()
+	uploadErrCh := make(chan error, 1)
+
+	go func() {
+		uploadErrCh <- client.Put(ctx, pr, dst)
+	}()
+
+	closeFn := func(writeErr error) error {
+		if writeErr != nil {
+			pw.CloseWithError(writeErr)
+			<-uploadErrCh
+			return nil
+		}
+		if err := pw.Close(); err != nil {
+			return err
+		}
+		return <-uploadErrCh
+	}
+
+	return pw, closeFn, nil
+}
+
+// relativeKey returns the portion of objurl's path that is relative to
+// srcurl's non-wildcard directory, for use when deriving a per-object
+// destination key under a prefix.
+func relativeKey(srcurl, objurl *url.URL) string {
+	prefix := srcurl.Path
+	if idx := strings.IndexAny(prefix, "*?["); idx >= 0 {
+		prefix = prefix[:idx]
+	}
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		prefix = prefix[:i+1]
+	} else {
+		prefix = ""
+	}
+	return strings.TrimPrefix(objurl.Path, prefix)
+}
+
+func (s Select) prepareTask(ctx context.Context, client *storage.S3, objurl, srcurl *url.URL, sink *resultSink, stats *selectStats, progress *progressPrinter) func() error {
 	return func() error {
 		query := &storage.SelectQuery{
 			ExpressionType:  "SQL",
 			Expression:      s.query,
 			CompressionType: s.compressionType,
+
+			InputFormat:                    strings.ToLower(s.inputFormat),
+			InputJSONType:                  s.inputJSONType,
+			InputCSVDelimiter:              s.inputCSVDelimiter,
+			InputCSVQuote:                  s.inputCSVQuote,
+			InputCSVHeaderInfo:             strings.ToUpper(s.inputCSVHeaderInfo),
+			InputCSVComments:               s.inputCSVComments,
+			InputCSVAllowQuotedRecordDelim: s.inputCSVAllowQuotedRecordDelimiter,
+
+			OutputFormat:              strings.ToLower(s.outputFormat),
+			OutputJSONRecordDelimiter: s.outputJSONRecordDelimiter,
+			OutputCSVDelimiter:        s.outputCSVDelimiter,
+			OutputCSVQuote:            s.outputCSVQuote,
+			OutputCSVQuoteFields:      strings.ToUpper(s.outputCSVQuoteFields),
 		}
 
-		rc, err := client.Select(ctx, url, query, os.Stdout)
+		w, closeWriter, err := sink.writerFor(ctx, srcurl, objurl, query.Extension())
 		if err != nil {
 			return err
 		}
+
+		var onProgress storage.SelectProgressFunc
+		if s.printStats || progress != nil {
+			onProgress = func(ev storage.SelectEvent) {
+				// Continuation/End events carry no byte counters; only
+				// Stats/Progress events have anything to report here.
+				switch ev.Type {
+				case storage.SelectStatsEvent, storage.SelectProgressEvent:
+					if s.printStats {
+						stats.update(objurl.String(), ev.Stats)
+					}
+					if progress != nil {
+						progress.Update(objurl.String(), ev.Stats)
+					}
+				}
+			}
+		}
+
+		rc, err := client.Select(ctx, objurl, query, onProgress)
+		if err != nil {
+			closeWriter(err)
+			return err
+		}
 		defer rc.Close()
 
-		_, err = io.Copy(os.Stdout, rc)
-		return err
+		if _, err := io.Copy(w, rc); err != nil {
+			closeWriter(err)
+			return err
+		}
+
+		return closeWriter(nil)
 	}
 }
 
 func validateSelectCommand(c *cli.Context) error {
-	if c.Args().Len() != 1 {
-		return fmt.Errorf("expected source argument")
+	if c.Args().Len() != 1 && c.Args().Len() != 2 {
+		return fmt.Errorf("expected source and optional destination argument")
 	}
 
 	src := c.Args().Get(0)
@@ -180,5 +639,69 @@ func validateSelectCommand(c *cli.Context) error {
 		return fmt.Errorf("source must be remote")
 	}
 
+	if dst := c.Args().Get(1); dst != "" {
+		if _, err := url.New(dst); err != nil {
+			return err
+		}
+	}
+
+	inputFormat := strings.ToLower(c.String("input-format"))
+	switch inputFormat {
+	case "json", "csv", "parquet":
+	default:
+		return fmt.Errorf("unknown input format %q", inputFormat)
+	}
+
+	outputFormat := strings.ToLower(c.String("output-format"))
+	switch outputFormat {
+	case "json", "csv":
+	default:
+		return fmt.Errorf("unknown output format %q", outputFormat)
+	}
+
+	if inputFormat == "parquet" && c.String("compression-type") != "" {
+		return fmt.Errorf("--compression-type is not supported with parquet input")
+	}
+
+	if inputFormat == "parquet" && outputFormat != "json" {
+		return fmt.Errorf("parquet input only supports --output-format json")
+	}
+
+	if inputFormat != "csv" {
+		for _, flag := range []string{"csv-delimiter", "csv-quote", "csv-header", "csv-comments", "csv-allow-quoted-record-delimiter"} {
+			if c.IsSet(flag) {
+				return fmt.Errorf("--%s is only valid with --input-format csv", flag)
+			}
+		}
+	}
+
+	if c.IsSet("csv-header") {
+		switch strings.ToLower(c.String("csv-header")) {
+		case "none", "use", "ignore":
+		default:
+			return fmt.Errorf("unknown --csv-header value %q", c.String("csv-header"))
+		}
+	}
+
+	if outputFormat != "csv" {
+		for _, flag := range []string{"output-csv-delimiter", "output-csv-quote", "output-csv-quote-fields"} {
+			if c.IsSet(flag) {
+				return fmt.Errorf("--%s is only valid with --output-format csv", flag)
+			}
+		}
+	}
+
+	if outputFormat != "json" && c.IsSet("output-json-record-delimiter") {
+		return fmt.Errorf("--output-json-record-delimiter is only valid with --output-format json")
+	}
+
+	if c.IsSet("output-csv-quote-fields") {
+		switch strings.ToLower(c.String("output-csv-quote-fields")) {
+		case "always", "asneeded":
+		default:
+			return fmt.Errorf("unknown --output-csv-quote-fields value %q", c.String("output-csv-quote-fields"))
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,95 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/peak/s5cmd/storage"
+)
+
+// selectStats accumulates the Progress/Stats byte counters reported by S3
+// Select across every object processed by a single select run.
+type selectStats struct {
+	bytesScanned   int64
+	bytesProcessed int64
+	bytesReturned  int64
+
+	mu         sync.Mutex
+	perObject  map[string]storage.SelectStats
+	numObjects int64
+}
+
+// update records the latest cumulative counters reported for key (an
+// object's URL), replacing any previous value for the same object, since
+// each Progress/Stats event reports running totals rather than deltas.
+func (s *selectStats) update(key string, st storage.SelectStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.perObject == nil {
+		s.perObject = make(map[string]storage.SelectStats)
+	}
+
+	prev, ok := s.perObject[key]
+	if !ok {
+		atomic.AddInt64(&s.numObjects, 1)
+	}
+
+	atomic.AddInt64(&s.bytesScanned, st.BytesScanned-prev.BytesScanned)
+	atomic.AddInt64(&s.bytesProcessed, st.BytesProcessed-prev.BytesProcessed)
+	atomic.AddInt64(&s.bytesReturned, st.BytesReturned-prev.BytesReturned)
+
+	s.perObject[key] = st
+}
+
+// summary renders the stats in the same tabular style as `cp --stats`.
+func (s *selectStats) summary() string {
+	return fmt.Sprintf(
+		"\n%-20s%20d\n%-20s%20d\n%-20s%20d\n%-20s%20d",
+		"Objects:", atomic.LoadInt64(&s.numObjects),
+		"Bytes scanned:", atomic.LoadInt64(&s.bytesScanned),
+		"Bytes processed:", atomic.LoadInt64(&s.bytesProcessed),
+		"Bytes returned:", atomic.LoadInt64(&s.bytesReturned),
+	)
+}
+
+// progressPrinter writes a single, throttled, overwritten progress line to
+// an output stream (normally stderr), reporting whichever worker last
+// reported in, so that a busy select run with many concurrent workers
+// doesn't flood the terminal with one line per event.
+type progressPrinter struct {
+	w        io.Writer
+	interval time.Duration
+
+	mu       sync.Mutex
+	lastSent time.Time
+}
+
+func newProgressPrinter(w io.Writer, interval time.Duration) *progressPrinter {
+	return &progressPrinter{w: w, interval: interval}
+}
+
+// Update reports the latest counters for key (an object's URL). Calls
+// within interval of the previous one are dropped to keep the output
+// readable.
+func (p *progressPrinter) Update(key string, st storage.SelectStats) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(p.lastSent) < p.interval {
+		return
+	}
+	p.lastSent = now
+
+	fmt.Fprintf(p.w, "\r%s: scanned %d, processed %d, returned %d bytes",
+		key, st.BytesScanned, st.BytesProcessed, st.BytesReturned)
+}
+
+// Done writes the trailing newline that ends the progress line.
+func (p *progressPrinter) Done() {
+	fmt.Fprintln(p.w)
+}
@@ -0,0 +1,55 @@
+package command
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/peak/s5cmd/storage"
+)
+
+// TestSelectStatsUpdateAccumulatesDeltas exercises the case update exists
+// for: S3 Select reports cumulative totals per object on every event, so a
+// second update for the same key must add the difference since the last
+// update, not the raw totals again.
+func TestSelectStatsUpdateAccumulatesDeltas(t *testing.T) {
+	var stats selectStats
+
+	stats.update("s3://bucket/a", storage.SelectStats{BytesScanned: 100, BytesProcessed: 100, BytesReturned: 10})
+	stats.update("s3://bucket/a", storage.SelectStats{BytesScanned: 150, BytesProcessed: 150, BytesReturned: 15})
+
+	if stats.bytesScanned != 150 {
+		t.Errorf("bytesScanned = %d, want 150 (cumulative, not summed)", stats.bytesScanned)
+	}
+	if stats.numObjects != 1 {
+		t.Errorf("numObjects = %d, want 1, repeated updates for the same key must not recount it", stats.numObjects)
+	}
+
+	stats.update("s3://bucket/b", storage.SelectStats{BytesScanned: 50, BytesProcessed: 50, BytesReturned: 5})
+
+	if stats.bytesScanned != 200 {
+		t.Errorf("bytesScanned = %d, want 200 after a second object", stats.bytesScanned)
+	}
+	if stats.numObjects != 2 {
+		t.Errorf("numObjects = %d, want 2", stats.numObjects)
+	}
+}
+
+// TestProgressPrinterThrottles checks that an Update call within interval
+// of the previous one is dropped, so a busy run with many workers doesn't
+// flood the terminal with one line per event.
+func TestProgressPrinterThrottles(t *testing.T) {
+	var buf bytes.Buffer
+	p := newProgressPrinter(&buf, time.Hour)
+
+	p.Update("s3://bucket/a", storage.SelectStats{BytesScanned: 10})
+	if buf.Len() == 0 {
+		t.Fatal("expected the first Update to write a progress line")
+	}
+
+	written := buf.Len()
+	p.Update("s3://bucket/b", storage.SelectStats{BytesScanned: 20})
+	if buf.Len() != written {
+		t.Errorf("expected the second Update within the interval to be suppressed, buf grew from %d to %d bytes", written, buf.Len())
+	}
+}
@@ -0,0 +1,210 @@
+package command
+
+import (
+	"bytes"
+	"flag"
+	"reflect"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/storage/filter"
+)
+
+// newSelectTestContext builds a *cli.Context wired up with selectCommandFlags
+// and args, without going through cli.App.Run, so that validateSelectCommand
+// can be exercised directly without reaching Select.Run's Action.
+//
+// This snapshot of the tree has no mock S3 server harness for the rest of
+// the test suite to share, so these are flag-validation unit tests rather
+// than the end-to-end combination tests against a live Select call.
+func newSelectTestContext(t *testing.T, args []string) *cli.Context {
+	t.Helper()
+
+	set := flag.NewFlagSet("select", flag.ContinueOnError)
+	for _, f := range selectCommandFlags {
+		if err := f.Apply(set); err != nil {
+			t.Fatalf("applying flag: %v", err)
+		}
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatalf("parsing args: %v", err)
+	}
+
+	return cli.NewContext(nil, set, nil)
+}
+
+// TestSyncWriterSeparatesObjectsNotWrites exercises the case that made the
+// separator real bug: a single object's select result commonly arrives as
+// several RecordsEvent frames, each surfacing as its own Write call, rather
+// than one Write per object. The separator must land once per object, not
+// once per frame.
+func TestSyncWriterSeparatesObjectsNotWrites(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &resultSink{mode: "concat", shared: &buf, resultSeparator: "---\n"}
+
+	first := &syncWriter{sink: sink}
+	for _, frame := range [][]byte{[]byte("rec1\n"), []byte("rec2\n")} {
+		if _, err := first.Write(frame); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	second := &syncWriter{sink: sink}
+	for _, frame := range [][]byte{[]byte("rec3\n"), []byte("rec4\n")} {
+		if _, err := second.Write(frame); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	want := "rec1\nrec2\n---\nrec3\nrec4\n"
+	if got := buf.String(); got != want {
+		t.Errorf("buf = %q, want %q", got, want)
+	}
+}
+
+// TestPatternFlagPerInvocation exercises patternFlag.Apply against two
+// independent *flag.FlagSets, standing in for two concurrent "select"
+// invocations (e.g. via "run"), and checks that neither invocation's
+// --exclude/--include patterns leak into the other's.
+func TestPatternFlagPerInvocation(t *testing.T) {
+	parse := func(args []string) []filter.Pattern {
+		set := flag.NewFlagSet("select", flag.ContinueOnError)
+		exclude := &patternFlag{name: "exclude", include: false}
+		include := &patternFlag{name: "include", include: true}
+		if err := exclude.Apply(set); err != nil {
+			t.Fatalf("Apply(exclude): %v", err)
+		}
+		if err := include.Apply(set); err != nil {
+			t.Fatalf("Apply(include): %v", err)
+		}
+		if err := set.Parse(args); err != nil {
+			t.Fatalf("Parse(): %v", err)
+		}
+		c := cli.NewContext(nil, set, nil)
+		v, ok := c.Generic("exclude").(*patternValue)
+		if !ok {
+			t.Fatalf("Generic(exclude) did not return a *patternValue")
+		}
+		return *v.patterns
+	}
+
+	a := parse([]string{"--include", "important.log", "--exclude", "*.log"})
+	b := parse([]string{"--exclude", "other.csv"})
+
+	wantA := []filter.Pattern{{Pattern: "important.log", Include: true}, {Pattern: "*.log", Include: false}}
+	if !reflect.DeepEqual(a, wantA) {
+		t.Errorf("a = %+v, want %+v", a, wantA)
+	}
+
+	wantB := []filter.Pattern{{Pattern: "other.csv", Include: false}}
+	if !reflect.DeepEqual(b, wantB) {
+		t.Errorf("b = %+v, want %+v", b, wantB)
+	}
+}
+
+func TestValidateSelectCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			name: "source only",
+			args: []string{"s3://bucket/*"},
+		},
+		{
+			name: "source and destination",
+			args: []string{"s3://bucket/*", "s3://bucket/results/"},
+		},
+		{
+			name:    "no arguments",
+			wantErr: true,
+		},
+		{
+			name:    "too many arguments",
+			args:    []string{"s3://bucket/*", "s3://bucket/results/", "extra"},
+			wantErr: true,
+		},
+		{
+			name:    "source must be remote",
+			args:    []string{"/local/path"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid destination url",
+			args:    []string{"s3://bucket/*", "://not-a-url"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown input format",
+			args:    []string{"--input-format", "xml", "s3://bucket/*"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown output format",
+			args:    []string{"--output-format", "xml", "s3://bucket/*"},
+			wantErr: true,
+		},
+		{
+			name:    "parquet input rejects compression",
+			args:    []string{"--input-format", "parquet", "--compression-type", "gzip", "s3://bucket/*"},
+			wantErr: true,
+		},
+		{
+			name: "parquet input without compression",
+			args: []string{"--input-format", "parquet", "s3://bucket/*"},
+		},
+		{
+			name:    "parquet input rejects csv output",
+			args:    []string{"--input-format", "parquet", "--output-format", "csv", "s3://bucket/*"},
+			wantErr: true,
+		},
+		{
+			name:    "csv flag rejected with json input",
+			args:    []string{"--csv-delimiter", ";", "s3://bucket/*"},
+			wantErr: true,
+		},
+		{
+			name: "csv flag accepted with csv input",
+			args: []string{"--input-format", "csv", "--csv-delimiter", ";", "s3://bucket/*"},
+		},
+		{
+			name:    "unknown csv-header value",
+			args:    []string{"--input-format", "csv", "--csv-header", "bogus", "s3://bucket/*"},
+			wantErr: true,
+		},
+		{
+			name:    "output-csv flag rejected with json output",
+			args:    []string{"--output-csv-delimiter", ";", "s3://bucket/*"},
+			wantErr: true,
+		},
+		{
+			name: "output-csv flag accepted with csv output",
+			args: []string{"--output-format", "csv", "--output-csv-delimiter", ";", "s3://bucket/*"},
+		},
+		{
+			name:    "output-json-record-delimiter rejected with csv output",
+			args:    []string{"--output-format", "csv", "--output-json-record-delimiter", "\n", "s3://bucket/*"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown output-csv-quote-fields value",
+			args:    []string{"--output-format", "csv", "--output-csv-quote-fields", "bogus", "s3://bucket/*"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newSelectTestContext(t, tc.args)
+			err := validateSelectCommand(c)
+			if tc.wantErr && err == nil {
+				t.Fatalf("expected an error, got nil")
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatcherPrecedence(t *testing.T) {
+	m, err := New(nil, []Pattern{
+		{Pattern: "*.log", Include: false},
+		{Pattern: "important.log", Include: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "important.log" is listed as an include, but it comes after the
+	// "*.log" exclude in pattern order, so the exclude still wins.
+	if m.Match("important.log") {
+		t.Errorf("expected important.log to be excluded, patterns are evaluated in order")
+	}
+
+	if m.Match("access.log") {
+		t.Errorf("expected access.log to be excluded by *.log")
+	}
+
+	if !m.Match("data.csv") {
+		t.Errorf("expected data.csv to be included, it matches no pattern")
+	}
+}
+
+// TestMatcherOrderFollowsArgvOrder exercises New itself with the
+// --include/--exclude patterns in the exact interleaved order they would
+// appear on the command line, rather than hand-building a Matcher. This
+// covers the --include-before-its-conflicting---exclude case, which New
+// can only get right if it's handed patterns that are already in argv
+// order: unlike the earlier version of this test, it doesn't assume any
+// implicit "all excludes, then all includes" grouping.
+func TestMatcherOrderFollowsArgvOrder(t *testing.T) {
+	// command line: --include important.log --exclude *.log
+	m, err := New(nil, []Pattern{
+		{Pattern: "important.log", Include: true},
+		{Pattern: "*.log", Include: false},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !m.Match("important.log") {
+		t.Errorf("expected important.log to be included, its pattern comes first")
+	}
+	if m.Match("access.log") {
+		t.Errorf("expected access.log to be excluded by *.log")
+	}
+
+	// Flip the order on the command line: --exclude *.log --include important.log
+	m, err = New(nil, []Pattern{
+		{Pattern: "*.log", Include: false},
+		{Pattern: "important.log", Include: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Match("important.log") {
+		t.Errorf("expected important.log to be excluded once *.log is given first")
+	}
+}
+
+func TestMatcherTrailingSlashDirectory(t *testing.T) {
+	m, err := New(nil, []Pattern{{Pattern: "logs/", Include: false}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Match("logs/2020/01/01.log") {
+		t.Errorf("expected objects under logs/ to be excluded")
+	}
+	if !m.Match("other/2020/01/01.log") {
+		t.Errorf("expected objects outside logs/ to be included")
+	}
+}
+
+func TestMatcherSlashlessPatternMatchesAnyDepth(t *testing.T) {
+	// a pattern with no "/" is rsync-like: it matches the final path
+	// component at any depth, not only a flat, top-level key.
+	m, err := New(nil, []Pattern{{Pattern: "*.log", Include: false}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Match("logs/2020/01/01.log") {
+		t.Errorf("expected a nested key to be excluded by the slash-less *.log pattern")
+	}
+	if !m.Match("logs/2020/01/01.csv") {
+		t.Errorf("expected a non-matching nested key to be included")
+	}
+}
+
+func TestMatcherNegatedPattern(t *testing.T) {
+	// command line: --include *.csv --exclude *
+	// the include must precede the catch-all exclude to have any effect,
+	// since Match stops at the first pattern that matches.
+	m, err := New(nil, []Pattern{
+		{Pattern: "*.csv", Include: true},
+		{Pattern: "*", Include: false},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !m.Match("data.csv") {
+		t.Errorf("expected data.csv to be included, its pattern comes first")
+	}
+	if m.Match("data.json") {
+		t.Errorf("expected data.json to be excluded by the catch-all *")
+	}
+}
+
+func TestMatcherExcludeFromPrecedesPatterns(t *testing.T) {
+	m, err := New(nil, []Pattern{{Pattern: "data.csv", Include: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Match("data.csv") {
+		t.Errorf("expected data.csv to be included, no exclude-from file was given")
+	}
+
+	file := filepath.Join(t.TempDir(), "exclude.txt")
+	if err := os.WriteFile(file, []byte("# comment\n\ndata.csv\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err = New([]string{file}, []Pattern{{Pattern: "data.csv", Include: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Match("data.csv") {
+		t.Errorf("expected data.csv to be excluded, exclude-from patterns are evaluated before the include")
+	}
+}
+
+func TestMatcherNilMatchesEverything(t *testing.T) {
+	var m *Matcher
+	if !m.Match("anything") {
+		t.Errorf("expected a nil Matcher to match everything")
+	}
+}
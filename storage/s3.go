@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+
+	"github.com/peak/s5cmd/storage/url"
+)
+
+// Options stores configuration for the S3 client, filled in from global
+// command line flags.
+type Options struct {
+	MaxRetries    int
+	Endpoint      string
+	NoVerifySSL   bool
+	NoSignRequest bool
+
+	Concurrency int
+	PartSize    int64
+}
+
+// S3 is a storage client for Amazon S3 and compatible services.
+type S3 struct {
+	api  *s3.S3
+	opts Options
+}
+
+// NewRemoteClient creates an S3 client for the bucket referenced by url,
+// using the given options.
+func NewRemoteClient(ctx context.Context, url *url.URL, opts Options) (*S3, error) {
+	awsCfg := aws.NewConfig().WithMaxRetries(opts.MaxRetries)
+
+	if opts.NoSignRequest {
+		awsCfg = awsCfg.WithCredentials(credentials.AnonymousCredentials)
+	}
+
+	if opts.Endpoint != "" {
+		awsCfg = awsCfg.WithEndpoint(opts.Endpoint).WithS3ForcePathStyle(true)
+	}
+
+	if opts.NoVerifySSL {
+		awsCfg = awsCfg.WithHTTPClient(&http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		})
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *awsCfg,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3{api: s3.New(sess), opts: opts}, nil
+}
+
+// SelectStats reports the byte counters carried by a single S3 Select
+// Progress or Stats event.
+type SelectStats struct {
+	BytesScanned   int64
+	BytesProcessed int64
+	BytesReturned  int64
+}
+
+// SelectEventType identifies which kind of event on the select event stream
+// a SelectProgressFunc was invoked for.
+type SelectEventType int
+
+const (
+	SelectStatsEvent SelectEventType = iota
+	SelectProgressEvent
+	SelectContinuationEvent
+	SelectEndEvent
+)
+
+// SelectEvent is passed to a SelectProgressFunc for every Stats, Progress,
+// Continuation and End event seen on the select event stream. Stats is only
+// populated for SelectStatsEvent and SelectProgressEvent; Continuation and
+// End events carry no byte counters.
+type SelectEvent struct {
+	Type  SelectEventType
+	Stats SelectStats
+}
+
+// SelectProgressFunc is invoked for every Progress, Stats, Continuation and
+// End event frame seen on the select event stream, in addition to the
+// record payloads already flowing through the returned reader.
+type SelectProgressFunc func(SelectEvent)
+
+// Select runs an S3 Select query against the object at url and returns a
+// ReadCloser over the concatenated record payloads. fn, if non-nil, is
+// called for every Progress, Stats, Continuation and End event as it
+// arrives on the event stream; only Progress and Stats events carry the
+// running byte totals.
+func (s *S3) Select(ctx context.Context, url *url.URL, query *SelectQuery, fn SelectProgressFunc) (io.ReadCloser, error) {
+	input, err := query.InputSerialization()
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := query.OutputSerialization()
+	if err != nil {
+		return nil, err
+	}
+
+	req := &s3.SelectObjectContentInput{
+		Bucket:              aws.String(url.Bucket),
+		Key:                 aws.String(url.Path),
+		ExpressionType:      aws.String(query.ExpressionType),
+		Expression:          aws.String(query.Expression),
+		InputSerialization:  input,
+		OutputSerialization: output,
+	}
+
+	resp, err := s.api.SelectObjectContentWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+
+	go func() {
+		stream := resp.EventStream
+		defer stream.Close()
+
+		for event := range stream.Events() {
+			switch e := event.(type) {
+			case *s3.RecordsEvent:
+				if _, err := pw.Write(e.Payload); err != nil {
+					pw.CloseWithError(err)
+					return
+				}
+			case *s3.StatsEvent:
+				if fn != nil && e.Details != nil {
+					fn(SelectEvent{Type: SelectStatsEvent, Stats: statsFromStats(e.Details)})
+				}
+			case *s3.ProgressEvent:
+				if fn != nil && e.Details != nil {
+					fn(SelectEvent{Type: SelectProgressEvent, Stats: statsFromProgress(e.Details)})
+				}
+			case *s3.ContinuationEvent:
+				if fn != nil {
+					fn(SelectEvent{Type: SelectContinuationEvent})
+				}
+			case *s3.EndEvent:
+				if fn != nil {
+					fn(SelectEvent{Type: SelectEndEvent})
+				}
+				pw.Close()
+				return
+			}
+		}
+
+		pw.CloseWithError(stream.Err())
+	}()
+
+	return pr, nil
+}
+
+func statsFromStats(d *s3.Stats) SelectStats {
+	return SelectStats{
+		BytesScanned:   aws.Int64Value(d.BytesScanned),
+		BytesProcessed: aws.Int64Value(d.BytesProcessed),
+		BytesReturned:  aws.Int64Value(d.BytesReturned),
+	}
+}
+
+func statsFromProgress(d *s3.Progress) SelectStats {
+	return SelectStats{
+		BytesScanned:   aws.Int64Value(d.BytesScanned),
+		BytesProcessed: aws.Int64Value(d.BytesProcessed),
+		BytesReturned:  aws.Int64Value(d.BytesReturned),
+	}
+}
+
+// Put uploads the contents of r to the object at url. It is used to write
+// single-object results, such as the output of a select query, without
+// going through the metadata-preserving copy path used by cp.
+func (s *S3) Put(ctx context.Context, r io.Reader, url *url.URL) error {
+	uploader := s3manager.NewUploaderWithClient(s.api, func(u *s3manager.Uploader) {
+		if s.opts.Concurrency > 0 {
+			u.Concurrency = s.opts.Concurrency
+		}
+		if s.opts.PartSize > 0 {
+			u.PartSize = s.opts.PartSize
+		}
+	})
+
+	_, err := uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(url.Bucket),
+		Key:    aws.String(url.Path),
+		Body:   r,
+	})
+	return err
+}
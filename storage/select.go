@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// SelectQuery is a constraint structure for S3 Select queries. It describes
+// the expression to run along with how the source object should be parsed
+// and how results should be serialized back to the caller.
+type SelectQuery struct {
+	ExpressionType  string
+	Expression      string
+	CompressionType string
+
+	// input
+	InputFormat                    string
+	InputJSONType                  string
+	InputCSVDelimiter              string
+	InputCSVQuote                  string
+	InputCSVHeaderInfo             string
+	InputCSVComments               string
+	InputCSVAllowQuotedRecordDelim bool
+
+	// output
+	OutputFormat              string
+	OutputJSONRecordDelimiter string
+	OutputCSVDelimiter        string
+	OutputCSVQuote            string
+	OutputCSVQuoteFields      string
+}
+
+// InputSerialization builds the s3.InputSerialization value that corresponds
+// to the query's input format and compression type.
+func (q *SelectQuery) InputSerialization() (*s3.InputSerialization, error) {
+	serialization := &s3.InputSerialization{}
+
+	if q.CompressionType != "" {
+		serialization.CompressionType = aws.String(q.CompressionType)
+	}
+
+	switch q.InputFormat {
+	case "", "json":
+		serialization.JSON = &s3.JSONInput{
+			Type: aws.String(defaultString(q.InputJSONType, "DOCUMENT")),
+		}
+	case "csv":
+		serialization.CSV = &s3.CSVInput{
+			FieldDelimiter:             aws.String(defaultString(q.InputCSVDelimiter, ",")),
+			QuoteCharacter:             aws.String(defaultString(q.InputCSVQuote, `"`)),
+			FileHeaderInfo:             aws.String(defaultString(q.InputCSVHeaderInfo, "NONE")),
+			AllowQuotedRecordDelimiter: aws.Bool(q.InputCSVAllowQuotedRecordDelim),
+		}
+		// Comments is only set when --csv-comments was actually given: an
+		// explicit empty string is a distinct value to the S3 Select API
+		// from an absent field, and isn't guaranteed to behave like the
+		// service's own default comment handling.
+		if q.InputCSVComments != "" {
+			serialization.CSV.Comments = aws.String(q.InputCSVComments)
+		}
+	case "parquet":
+		if q.CompressionType != "" {
+			return nil, fmt.Errorf("parquet input does not support compression")
+		}
+		serialization.Parquet = &s3.ParquetInput{}
+	default:
+		return nil, fmt.Errorf("unknown input format %q", q.InputFormat)
+	}
+
+	return serialization, nil
+}
+
+// OutputSerialization builds the s3.OutputSerialization value that
+// corresponds to the query's output format.
+func (q *SelectQuery) OutputSerialization() (*s3.OutputSerialization, error) {
+	serialization := &s3.OutputSerialization{}
+
+	switch q.OutputFormat {
+	case "", "json":
+		serialization.JSON = &s3.JSONOutput{
+			RecordDelimiter: aws.String(defaultString(q.OutputJSONRecordDelimiter, "\n")),
+		}
+	case "csv":
+		serialization.CSV = &s3.CSVOutput{
+			FieldDelimiter: aws.String(defaultString(q.OutputCSVDelimiter, ",")),
+			QuoteCharacter: aws.String(defaultString(q.OutputCSVQuote, `"`)),
+			QuoteFields:    aws.String(defaultString(q.OutputCSVQuoteFields, "ASNEEDED")),
+		}
+	default:
+		return nil, fmt.Errorf("unknown output format %q", q.OutputFormat)
+	}
+
+	return serialization, nil
+}
+
+// Extension returns the file extension that should be used for results
+// produced with this query's output format.
+func (q *SelectQuery) Extension() string {
+	if q.OutputFormat == "csv" {
+		return "csv"
+	}
+	return "json"
+}
+
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,110 @@
+package storage
+
+import "testing"
+
+func TestSelectQueryInputSerializationCSVComments(t *testing.T) {
+	serialization, err := (&SelectQuery{InputFormat: "csv"}).InputSerialization()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serialization.CSV.Comments != nil {
+		t.Errorf("Comments = %v, want nil when --csv-comments was not given", *serialization.CSV.Comments)
+	}
+
+	serialization, err = (&SelectQuery{InputFormat: "csv", InputCSVComments: "#"}).InputSerialization()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serialization.CSV.Comments == nil || *serialization.CSV.Comments != "#" {
+		t.Errorf("Comments = %v, want \"#\"", serialization.CSV.Comments)
+	}
+}
+
+func TestSelectQueryInputSerialization(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   SelectQuery
+		wantErr bool
+	}{
+		{
+			name:  "default json",
+			query: SelectQuery{},
+		},
+		{
+			name:  "csv with explicit delimiters",
+			query: SelectQuery{InputFormat: "csv", InputCSVDelimiter: ";", InputCSVQuote: "'", InputCSVHeaderInfo: "USE"},
+		},
+		{
+			name:  "parquet",
+			query: SelectQuery{InputFormat: "parquet"},
+		},
+		{
+			name:    "parquet rejects compression",
+			query:   SelectQuery{InputFormat: "parquet", CompressionType: "gzip"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown input format",
+			query:   SelectQuery{InputFormat: "xml"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.query.InputSerialization()
+			if tc.wantErr && err == nil {
+				t.Fatalf("expected an error, got nil")
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSelectQueryOutputSerialization(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   SelectQuery
+		wantErr bool
+	}{
+		{
+			name:  "default json",
+			query: SelectQuery{},
+		},
+		{
+			name:  "csv with quote fields always",
+			query: SelectQuery{OutputFormat: "csv", OutputCSVQuoteFields: "ALWAYS"},
+		},
+		{
+			name:    "unknown output format",
+			query:   SelectQuery{OutputFormat: "xml"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.query.OutputSerialization()
+			if tc.wantErr && err == nil {
+				t.Fatalf("expected an error, got nil")
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSelectQueryExtension(t *testing.T) {
+	if ext := (&SelectQuery{OutputFormat: "csv"}).Extension(); ext != "csv" {
+		t.Errorf("Extension() = %q, want csv", ext)
+	}
+	if ext := (&SelectQuery{OutputFormat: "json"}).Extension(); ext != "json" {
+		t.Errorf("Extension() = %q, want json", ext)
+	}
+	if ext := (&SelectQuery{}).Extension(); ext != "json" {
+		t.Errorf("Extension() = %q, want json for the default format", ext)
+	}
+}
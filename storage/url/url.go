@@ -0,0 +1,78 @@
+// Package url provides a parsed representation of source and destination
+// arguments accepted by s5cmd commands.
+package url
+
+import (
+	"fmt"
+	"strings"
+)
+
+// URL represents an S3 or local file system location.
+type URL struct {
+	Type   string // "s3" or "fs"
+	Bucket string
+	Path   string
+}
+
+// New parses s into a URL, determining whether it refers to an S3 object or
+// a local file system path.
+func New(s string) (*URL, error) {
+	if !strings.HasPrefix(s, "s3://") {
+		if strings.Contains(s, "://") {
+			return nil, fmt.Errorf("unsupported url scheme in %q", s)
+		}
+		return &URL{Type: "fs", Path: s}, nil
+	}
+
+	rest := strings.TrimPrefix(s, "s3://")
+	if rest == "" {
+		return nil, fmt.Errorf("s3 url: bucket name cannot be empty")
+	}
+
+	parts := strings.SplitN(rest, "/", 2)
+	u := &URL{Type: "s3", Bucket: parts[0]}
+	if len(parts) == 2 {
+		u.Path = parts[1]
+	}
+	return u, nil
+}
+
+// IsRemote reports whether the URL refers to an S3 object.
+func (u *URL) IsRemote() bool {
+	return u.Type == "s3"
+}
+
+// IsWildcard reports whether the URL's path contains glob characters.
+func (u *URL) IsWildcard() bool {
+	return strings.ContainsAny(u.Path, "*?[]")
+}
+
+// Join returns a new URL pointing at elem appended to u's path, separated
+// by a "/".
+func (u *URL) Join(elem string) *URL {
+	joined := &URL{Type: u.Type, Bucket: u.Bucket, Path: u.Path}
+	if joined.Path == "" {
+		joined.Path = elem
+	} else {
+		joined.Path = strings.TrimSuffix(joined.Path, "/") + "/" + elem
+	}
+	return joined
+}
+
+// IsPrefix reports whether the URL refers to a directory-like location,
+// i.e. one whose path is empty (a bare bucket) or ends in "/". Wildcard
+// characters in the path do not make it a prefix.
+func (u *URL) IsPrefix() bool {
+	return u.Path == "" || strings.HasSuffix(u.Path, "/")
+}
+
+// String returns the original-form representation of the URL.
+func (u *URL) String() string {
+	if !u.IsRemote() {
+		return u.Path
+	}
+	if u.Path == "" {
+		return "s3://" + u.Bucket
+	}
+	return "s3://" + u.Bucket + "/" + u.Path
+}